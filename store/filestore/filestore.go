@@ -0,0 +1,110 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filestore implements a store.Store backed by a directory on
+// the local filesystem.
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/nelhage/llama/store"
+	"github.com/nelhage/llama/store/internal/storeutil"
+	"github.com/nelhage/llama/store/registry"
+)
+
+func init() {
+	registry.Register("file", func(ctx context.Context, address string) (store.Store, error) {
+		return New(address)
+	})
+}
+
+// Store is a store.Store backed by a directory on the local
+// filesystem. Objects are stored as individual files named by their
+// content hash.
+type Store struct {
+	dir string
+}
+
+// New constructs a filestore.Store rooted at the path in address,
+// which must have the scheme "file". The directory is created if it
+// does not already exist.
+func New(address string) (*Store, error) {
+	u, e := url.Parse(address)
+	if e != nil {
+		return nil, fmt.Errorf("parsing store: %q: %w", address, e)
+	}
+	if u.Scheme != "file" {
+		return nil, fmt.Errorf("object store: %q: unsupported scheme %s", address, u.Scheme)
+	}
+	dir := u.Path
+	if dir == "" {
+		dir = u.Opaque
+	}
+	if e := os.MkdirAll(dir, 0755); e != nil {
+		return nil, fmt.Errorf("creating store directory: %q: %w", dir, e)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *Store) Store(ctx context.Context, obj []byte) (string, error) {
+	id := storeutil.HashObject(obj)
+	path := s.path(id)
+	if _, err := os.Stat(path); err == nil {
+		return id, nil
+	}
+	tmp, err := ioutil.TempFile(s.dir, "tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(obj); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *Store) getOne(id string) ([]byte, error) {
+	body, err := ioutil.ReadFile(s.path(id))
+	if err != nil {
+		return nil, err
+	}
+	gotId := storeutil.HashObject(body)
+	if gotId != id {
+		return nil, fmt.Errorf("object store mismatch: got csum=%s expected %s", gotId, id)
+	}
+	return body, nil
+}
+
+func (s *Store) GetObjects(ctx context.Context, gets []store.GetRequest) {
+	for i := range gets {
+		gets[i].Data, gets[i].Err = s.getOne(gets[i].Id)
+	}
+}
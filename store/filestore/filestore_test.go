@@ -0,0 +1,78 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/nelhage/llama/store"
+)
+
+func TestStoreAndGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	s, err := New("file://" + dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	want := []byte("hello, filestore")
+	id, err := s.Store(ctx, want)
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	// Storing the same content again should be idempotent.
+	if id2, err := s.Store(ctx, want); err != nil || id2 != id {
+		t.Fatalf("second Store: id=%q err=%v, want id=%q err=nil", id2, err, id)
+	}
+
+	gets := []store.GetRequest{{Id: id}}
+	s.GetObjects(ctx, gets)
+	if gets[0].Err != nil {
+		t.Fatalf("GetObjects: %v", gets[0].Err)
+	}
+	if string(gets[0].Data) != string(want) {
+		t.Errorf("GetObjects = %q, want %q", gets[0].Data, want)
+	}
+}
+
+func TestGetMissingObject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	s, err := New("file://" + dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	gets := []store.GetRequest{{Id: "does-not-exist"}}
+	s.GetObjects(ctx, gets)
+	if gets[0].Err == nil {
+		t.Fatal("expected an error fetching a missing object")
+	}
+}
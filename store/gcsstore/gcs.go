@@ -0,0 +1,129 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gcsstore implements a store.Store backed by Google Cloud
+// Storage.
+package gcsstore
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/nelhage/llama/store"
+	"github.com/nelhage/llama/store/internal/storeutil"
+	"github.com/nelhage/llama/store/registry"
+	"github.com/nelhage/llama/tracing"
+)
+
+func init() {
+	registry.Register("gs", func(ctx context.Context, address string) (store.Store, error) {
+		return New(ctx, address)
+	})
+}
+
+// Store is a store.Store backed by a Google Cloud Storage bucket.
+type Store struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+// New constructs a gcsstore.Store using application-default
+// credentials. address must have the scheme "gs", with the bucket as
+// host and an optional key prefix as path, e.g.
+// "gs://my-bucket/llama/objects".
+func New(ctx context.Context, address string) (*Store, error) {
+	u, e := url.Parse(address)
+	if e != nil {
+		return nil, fmt.Errorf("parsing store: %q: %w", address, e)
+	}
+	if u.Scheme != "gs" {
+		return nil, fmt.Errorf("object store: %q: unsupported scheme %s", address, u.Scheme)
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		client: client,
+		bucket: u.Host,
+		prefix: u.Path,
+	}, nil
+}
+
+func (s *Store) object(id string) *storage.ObjectHandle {
+	return s.client.Bucket(s.bucket).Object(path.Join(s.prefix, id))
+}
+
+func (s *Store) Store(ctx context.Context, obj []byte) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "gcs.store")
+	defer span.End()
+	id := storeutil.HashObject(obj)
+
+	span.AddField("object_id", id)
+
+	if _, err := s.object(id).Attrs(ctx); err == nil {
+		span.AddField("gcs.exists", true)
+		return id, nil
+	} else if err != storage.ErrObjectNotExist {
+		return "", err
+	}
+
+	span.AddField("gcs.write_bytes", len(obj))
+
+	w := s.object(id).NewWriter(ctx)
+	if _, err := w.Write(obj); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *Store) getOne(ctx context.Context, id string) ([]byte, error) {
+	ctx, span := tracing.StartSpan(ctx, "gcs.get_one")
+	defer span.End()
+
+	r, err := s.object(id).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	gotId := storeutil.HashObject(body)
+	if gotId != id {
+		return nil, fmt.Errorf("object store mismatch: got csum=%s expected %s", gotId, id)
+	}
+
+	span.AddField("gcs.read_bytes", len(body))
+	return body, nil
+}
+
+func (s *Store) GetObjects(ctx context.Context, gets []store.GetRequest) {
+	ctx, span := tracing.StartSpan(ctx, "gcs.get_objects")
+	defer span.End()
+	for i := range gets {
+		gets[i].Data, gets[i].Err = s.getOne(ctx, gets[i].Id)
+	}
+}
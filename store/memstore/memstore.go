@@ -0,0 +1,79 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memstore implements an in-memory store.Store, primarily
+// useful for tests and for short-lived local invocations that don't
+// need durability.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/nelhage/llama/store"
+	"github.com/nelhage/llama/store/internal/storeutil"
+	"github.com/nelhage/llama/store/registry"
+)
+
+func init() {
+	registry.Register("mem", func(ctx context.Context, address string) (store.Store, error) {
+		return New(address)
+	})
+}
+
+// Store is an in-memory store.Store backed by a map. It is safe for
+// concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+// New constructs a memstore.Store. address must have the scheme
+// "mem"; its host and path are ignored, since Store does not persist
+// or share state outside the process.
+func New(address string) (*Store, error) {
+	u, e := url.Parse(address)
+	if e != nil {
+		return nil, fmt.Errorf("parsing store: %q: %w", address, e)
+	}
+	if u.Scheme != "mem" {
+		return nil, fmt.Errorf("object store: %q: unsupported scheme %s", address, u.Scheme)
+	}
+	return &Store{objects: make(map[string][]byte)}, nil
+}
+
+func (s *Store) Store(ctx context.Context, obj []byte) (string, error) {
+	id := storeutil.HashObject(obj)
+	cp := make([]byte, len(obj))
+	copy(cp, obj)
+	s.mu.Lock()
+	s.objects[id] = cp
+	s.mu.Unlock()
+	return id, nil
+}
+
+func (s *Store) GetObjects(ctx context.Context, gets []store.GetRequest) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range gets {
+		obj, ok := s.objects[gets[i].Id]
+		if !ok {
+			gets[i].Err = fmt.Errorf("memstore: no such object: %s", gets[i].Id)
+			continue
+		}
+		gets[i].Data = obj
+	}
+}
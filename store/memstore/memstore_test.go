@@ -0,0 +1,65 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nelhage/llama/store"
+)
+
+func TestStoreAndGet(t *testing.T) {
+	ctx := context.Background()
+	s, err := New("mem://")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	want := []byte("hello, memstore")
+	id, err := s.Store(ctx, want)
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	gets := []store.GetRequest{{Id: id}}
+	s.GetObjects(ctx, gets)
+	if gets[0].Err != nil {
+		t.Fatalf("GetObjects: %v", gets[0].Err)
+	}
+	if string(gets[0].Data) != string(want) {
+		t.Errorf("GetObjects = %q, want %q", gets[0].Data, want)
+	}
+}
+
+func TestGetMissingObject(t *testing.T) {
+	ctx := context.Background()
+	s, err := New("mem://")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	gets := []store.GetRequest{{Id: "does-not-exist"}}
+	s.GetObjects(ctx, gets)
+	if gets[0].Err == nil {
+		t.Fatal("expected an error fetching a missing object")
+	}
+}
+
+func TestNewRejectsUnsupportedScheme(t *testing.T) {
+	if _, err := New("file:///tmp"); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
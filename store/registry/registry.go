@@ -0,0 +1,73 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry maintains a registry of pluggable object store
+// backends, keyed by URL scheme. Backend packages call Register from
+// an init function, and callers use Open to construct a store.Store
+// without needing to import every backend directly.
+//
+// This is the intended replacement for call sites that construct an
+// s3store.Store directly from a CLI flag or config file: importing
+// the backend packages for side effect and calling registry.Open lets
+// llama support memstore/filestore/gcsstore addresses without adding
+// scheme-specific branches at every call site. The CLI and config
+// loading code that should be migrated to it are not part of this
+// change.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/nelhage/llama/store"
+)
+
+// Driver constructs a store.Store from a backend address whose scheme
+// it was registered under.
+type Driver func(ctx context.Context, address string) (store.Store, error)
+
+var (
+	mu      sync.Mutex
+	drivers = make(map[string]Driver)
+)
+
+// Register registers driver as the handler for URLs with the given
+// scheme. Register is intended to be called from the init function of
+// a backend package, and panics if scheme is already registered.
+func Register(scheme string, driver Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := drivers[scheme]; ok {
+		panic(fmt.Sprintf("registry: Register called twice for scheme %q", scheme))
+	}
+	drivers[scheme] = driver
+}
+
+// Open parses address and dispatches to the Driver registered for its
+// URL scheme.
+func Open(ctx context.Context, address string) (store.Store, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("parsing store address: %q: %w", address, err)
+	}
+	mu.Lock()
+	driver, ok := drivers[u.Scheme]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("object store: %q: unsupported scheme %s", address, u.Scheme)
+	}
+	return driver(ctx, address)
+}
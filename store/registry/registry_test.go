@@ -0,0 +1,55 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nelhage/llama/store"
+)
+
+func TestOpenUnknownScheme(t *testing.T) {
+	if _, err := Open(context.Background(), "bogus://whatever"); err == nil {
+		t.Fatal("expected an error opening an unregistered scheme")
+	}
+}
+
+func TestRegisterAndOpen(t *testing.T) {
+	const scheme = "registry-test"
+	Register(scheme, func(ctx context.Context, address string) (store.Store, error) {
+		return nil, nil
+	})
+
+	if _, err := Open(context.Background(), scheme+"://whatever"); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	const scheme = "registry-test-dup"
+	Register(scheme, func(ctx context.Context, address string) (store.Store, error) {
+		return nil, nil
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Register to panic on a duplicate scheme")
+		}
+	}()
+	Register(scheme, func(ctx context.Context, address string) (store.Store, error) {
+		return nil, nil
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3store
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// address is the result of parsing a store address into the pieces
+// needed to talk to a particular S3-compatible endpoint: which bucket
+// and key prefix to use, and, for non-AWS deployments, which endpoint,
+// addressing style, and credentials to use to reach it.
+type address struct {
+	bucket    string
+	prefix    string
+	endpoint  string
+	pathStyle bool
+	creds     *credentials.Credentials
+}
+
+// parseAddress parses a store address of one of the following forms:
+//
+//   - "s3://bucket/prefix", the standard AWS S3 form, using the
+//     session's default endpoint and credentials.
+//   - "s3://KEY:SECRET@endpoint/bucket/prefix", which supplies static
+//     credentials and a custom endpoint inline, for talking to a
+//     specific S3-compatible deployment without touching AWS
+//     environment variables.
+//   - "s3+http://host:port/bucket/prefix" or
+//     "s3+https://host:port/bucket/prefix", which imply a custom
+//     endpoint and path-style addressing, for MinIO, Ceph RGW, and
+//     similar services that are usually reached over a bare host:port
+//     with no DNS-based virtual-hosted buckets.
+func parseAddress(raw string) (*address, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing store: %q: %w", raw, err)
+	}
+
+	addr := &address{
+		bucket: u.Host,
+		prefix: u.Path,
+	}
+
+	switch u.Scheme {
+	case "s3":
+		if u.User != nil {
+			addr.endpoint = "https://" + u.Host
+			addr.bucket, addr.prefix = splitBucketPrefix(u.Path)
+		}
+	case "s3+http", "s3+https":
+		addr.pathStyle = true
+		addr.endpoint = strings.TrimPrefix(u.Scheme, "s3+") + "://" + u.Host
+		addr.bucket, addr.prefix = splitBucketPrefix(u.Path)
+	default:
+		return nil, fmt.Errorf("object store: %q: unsupported scheme %s", raw, u.Scheme)
+	}
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		addr.creds = credentials.NewStaticCredentials(u.User.Username(), password, "")
+	}
+
+	return addr, nil
+}
+
+// splitBucketPrefix splits a URL path of the form "/bucket/prefix"
+// into its bucket and prefix components, for address forms where the
+// bucket lives in the path rather than in the URL host.
+func splitBucketPrefix(urlPath string) (bucket, prefix string) {
+	trimmed := strings.TrimPrefix(urlPath, "/")
+	parts := strings.SplitN(trimmed, "/", 2)
+	bucket = parts[0]
+	if len(parts) == 2 {
+		prefix = "/" + parts[1]
+	}
+	return bucket, prefix
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3store
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	cases := []struct {
+		name      string
+		address   string
+		bucket    string
+		prefix    string
+		endpoint  string
+		pathStyle bool
+		hasCreds  bool
+	}{
+		{
+			name:    "plain s3",
+			address: "s3://my-bucket/prefix",
+			bucket:  "my-bucket",
+			prefix:  "/prefix",
+		},
+		{
+			name:      "minio path style",
+			address:   "s3+http://localhost:9000/my-bucket/prefix",
+			bucket:    "my-bucket",
+			prefix:    "/prefix",
+			endpoint:  "http://localhost:9000",
+			pathStyle: true,
+		},
+		{
+			name:     "inline credentials",
+			address:  "s3://AKIAEXAMPLE:secret@minio.internal:9000/my-bucket/prefix",
+			bucket:   "my-bucket",
+			prefix:   "/prefix",
+			endpoint: "https://minio.internal:9000",
+			hasCreds: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := parseAddress(tc.address)
+			if err != nil {
+				t.Fatalf("parseAddress(%q): %v", tc.address, err)
+			}
+			if addr.bucket != tc.bucket {
+				t.Errorf("bucket = %q, want %q", addr.bucket, tc.bucket)
+			}
+			if addr.prefix != tc.prefix {
+				t.Errorf("prefix = %q, want %q", addr.prefix, tc.prefix)
+			}
+			if addr.endpoint != tc.endpoint {
+				t.Errorf("endpoint = %q, want %q", addr.endpoint, tc.endpoint)
+			}
+			if addr.pathStyle != tc.pathStyle {
+				t.Errorf("pathStyle = %v, want %v", addr.pathStyle, tc.pathStyle)
+			}
+			if (addr.creds != nil) != tc.hasCreds {
+				t.Errorf("creds set = %v, want %v", addr.creds != nil, tc.hasCreds)
+			}
+		})
+	}
+}
+
+func TestParseAddressUnsupportedScheme(t *testing.T) {
+	if _, err := parseAddress("gs://my-bucket/prefix"); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
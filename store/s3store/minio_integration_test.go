@@ -0,0 +1,81 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3store
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// TestMinIOIntegration round-trips an object through a real
+// S3-compatible server reached via the s3+http address form. It is
+// skipped unless LLAMA_TEST_MINIO_ENDPOINT is set, e.g. to the address
+// of a local "minio server" container:
+//
+//	docker run -p 9000:9000 -e MINIO_ROOT_USER=minioadmin \
+//	    -e MINIO_ROOT_PASSWORD=minioadmin minio/minio server /data
+//	LLAMA_TEST_MINIO_ENDPOINT=localhost:9000 \
+//	LLAMA_TEST_MINIO_BUCKET=llama-test \
+//	LLAMA_TEST_MINIO_ACCESS_KEY=minioadmin \
+//	LLAMA_TEST_MINIO_SECRET_KEY=minioadmin \
+//	    go test ./store/s3store/ -run TestMinIOIntegration
+func TestMinIOIntegration(t *testing.T) {
+	endpoint := os.Getenv("LLAMA_TEST_MINIO_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("LLAMA_TEST_MINIO_ENDPOINT not set; skipping MinIO integration test")
+	}
+	bucket := os.Getenv("LLAMA_TEST_MINIO_BUCKET")
+	if bucket == "" {
+		bucket = "llama-test"
+	}
+	accessKey := os.Getenv("LLAMA_TEST_MINIO_ACCESS_KEY")
+	secretKey := os.Getenv("LLAMA_TEST_MINIO_SECRET_KEY")
+
+	address := fmt.Sprintf("s3+http://%s:%s@%s/%s/integration", accessKey, secretKey, endpoint, bucket)
+
+	s, err := session.NewSession()
+	if err != nil {
+		t.Fatalf("session.NewSession: %v", err)
+	}
+	store, err := FromSession(s, address)
+	if err != nil {
+		t.Fatalf("FromSession: %v", err)
+	}
+
+	ctx := context.Background()
+	want := []byte("hello from the minio integration test")
+	id, err := store.Store(ctx, want)
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	r, err := store.GetObjectStream(ctx, id)
+	if err != nil {
+		t.Fatalf("GetObjectStream: %v", err)
+	}
+	defer r.Close()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading object stream: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("GetObjectStream = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3store
+
+import "testing"
+
+func TestMultipartOptionDefaults(t *testing.T) {
+	s := &Store{}
+	if got := s.multipartThreshold(); got != defaultMultipartThreshold {
+		t.Errorf("multipartThreshold() = %d, want default %d", got, defaultMultipartThreshold)
+	}
+	if got := s.partSize(); got != defaultPartSize {
+		t.Errorf("partSize() = %d, want default %d", got, defaultPartSize)
+	}
+	if got := s.uploadConcurrency(); got != defaultUploadConcurrency {
+		t.Errorf("uploadConcurrency() = %d, want default %d", got, defaultUploadConcurrency)
+	}
+}
+
+func TestMultipartOptionOverrides(t *testing.T) {
+	s := &Store{opts: Options{
+		MultipartThreshold: 1 << 20,
+		PartSize:           2 << 20,
+		UploadConcurrency:  4,
+	}}
+	if got := s.multipartThreshold(); got != 1<<20 {
+		t.Errorf("multipartThreshold() = %d, want %d", got, 1<<20)
+	}
+	if got := s.partSize(); got != 2<<20 {
+		t.Errorf("partSize() = %d, want %d", got, 2<<20)
+	}
+	if got := s.uploadConcurrency(); got != 4 {
+		t.Errorf("uploadConcurrency() = %d, want %d", got, 4)
+	}
+}
@@ -17,11 +17,19 @@ package s3store
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/url"
+	"os"
 	"path"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -30,12 +38,72 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/nelhage/llama/store"
 	"github.com/nelhage/llama/store/internal/storeutil"
+	"github.com/nelhage/llama/store/registry"
 	"github.com/nelhage/llama/tracing"
 	"golang.org/x/sync/errgroup"
 )
 
+func init() {
+	driver := func(ctx context.Context, address string) (store.Store, error) {
+		s, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		return FromSession(s, address)
+	}
+	registry.Register("s3", driver)
+	registry.Register("s3+http", driver)
+	registry.Register("s3+https", driver)
+}
+
 type Options struct {
 	DisableHeadCheck bool
+
+	// Endpoint overrides the default AWS S3 endpoint, for use with
+	// S3-compatible object stores such as MinIO, Ceph, or Wasabi.
+	// Address forms that imply their own endpoint (see parseAddress)
+	// take precedence over this field.
+	Endpoint string
+	// S3ForcePathStyle forces path-style addressing (e.g.
+	// "https://s3.example.com/bucket/key" instead of
+	// "https://bucket.s3.example.com/key"), which most S3-compatible
+	// services require.
+	S3ForcePathStyle bool
+	// Region overrides the AWS region used to sign requests. Required
+	// by some S3-compatible services even when Endpoint is set.
+	Region string
+	// DisableSSL disables TLS for requests to Endpoint, for
+	// S3-compatible deployments reachable only over plain HTTP.
+	DisableSSL bool
+
+	// MultipartThreshold is the object size above which Store and
+	// StoreReader use S3's multipart upload API instead of a single
+	// PutObject call. Defaults to defaultMultipartThreshold (16MiB).
+	MultipartThreshold int64
+	// PartSize is the size of each part in a multipart upload. Must
+	// be at least 5MiB, per the S3 API. Defaults to defaultPartSize
+	// (16MiB).
+	PartSize int64
+	// UploadConcurrency bounds the number of parts uploaded in
+	// parallel during a multipart upload. Defaults to
+	// defaultUploadConcurrency (8).
+	UploadConcurrency int
+
+	// SSE selects server-side encryption for objects written by
+	// Store. Supported values are "" (no explicit SSE, the bucket
+	// default applies), "AES256" for SSE-S3, and "aws:kms" for
+	// SSE-KMS. It is ignored if SSECustomerKey is set.
+	SSE string
+	// SSEKMSKeyID optionally selects the KMS key to use when SSE is
+	// "aws:kms". If empty, S3 uses the bucket's default KMS key.
+	SSEKMSKeyID string
+
+	// SSECustomerKey, if set, enables SSE-C: the raw 256-bit key
+	// (not base64-encoded) used to encrypt and decrypt objects. When
+	// set, it takes precedence over SSE and SSEKMSKeyID, and must be
+	// supplied consistently across all readers and writers of the
+	// store.
+	SSECustomerKey string
 }
 
 type Store struct {
@@ -51,15 +119,32 @@ func FromSession(s *session.Session, address string) (*Store, error) {
 	return FromSessionAndOptions(s, address, Options{})
 }
 
-func FromSessionAndOptions(s *session.Session, address string, opts Options) (*Store, error) {
-	u, e := url.Parse(address)
-	if e != nil {
-		return nil, fmt.Errorf("Parsing store: %q: %w", address, e)
+func FromSessionAndOptions(s *session.Session, rawAddress string, opts Options) (*Store, error) {
+	addr, err := parseAddress(rawAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := aws.NewConfig().WithS3DisableContentMD5Validation(true)
+	if endpoint := addr.endpoint; endpoint != "" || opts.Endpoint != "" {
+		if endpoint == "" {
+			endpoint = opts.Endpoint
+		}
+		cfg = cfg.WithEndpoint(endpoint)
+	}
+	if opts.S3ForcePathStyle || addr.pathStyle {
+		cfg = cfg.WithS3ForcePathStyle(true)
 	}
-	if u.Scheme != "s3" {
-		return nil, fmt.Errorf("Object store: %q: unsupported scheme %s", address, u.Scheme)
+	if opts.Region != "" {
+		cfg = cfg.WithRegion(opts.Region)
 	}
-	svc := s3.New(s, aws.NewConfig().WithS3DisableContentMD5Validation(true))
+	if opts.DisableSSL {
+		cfg = cfg.WithDisableSSL(true)
+	}
+	if addr.creds != nil {
+		cfg = cfg.WithCredentials(addr.creds)
+	}
+	svc := s3.New(s, cfg)
 	svc.Handlers.Sign.PushFront(func(r *request.Request) {
 		r.HTTPRequest.Header.Add("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
 	})
@@ -67,18 +152,112 @@ func FromSessionAndOptions(s *session.Session, address string, opts Options) (*S
 		opts:    opts,
 		session: s,
 		s3:      svc,
-		url:     u,
+		url:     &url.URL{Host: addr.bucket, Path: addr.prefix},
 	}, nil
 }
 
+// sseCustomerHeaders returns the SSE-C algorithm, key, and key MD5 to
+// attach to a request, or zero values if SSECustomerKey is not set.
+// The key is passed through as the raw (not base64-encoded) bytes
+// from Options.SSECustomerKey: the SDK's own computeSSEKeys Build
+// handler base64-encodes SSECustomerKey and derives its MD5 from the
+// raw bytes, so pre-encoding it here would have the SDK re-encode an
+// already-encoded string and ship the wrong key. Computing the MD5
+// here rather than leaving it to the SDK is equivalent -- it also
+// hashes the raw bytes -- and keeps it alongside the key it was
+// derived from.
+func (s *Store) sseCustomerHeaders() (algorithm, key, keyMD5 *string) {
+	if s.opts.SSECustomerKey == "" {
+		return nil, nil, nil
+	}
+	sum := md5.Sum([]byte(s.opts.SSECustomerKey))
+	return aws.String("AES256"),
+		aws.String(s.opts.SSECustomerKey),
+		aws.String(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+func (s *Store) putObjectInput(key *string, body io.ReadSeeker) *s3.PutObjectInput {
+	in := &s3.PutObjectInput{
+		Body:   body,
+		Bucket: &s.url.Host,
+		Key:    key,
+	}
+	if alg, k, keyMD5 := s.sseCustomerHeaders(); alg != nil {
+		in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = alg, k, keyMD5
+	} else if s.opts.SSE != "" {
+		in.ServerSideEncryption = aws.String(s.opts.SSE)
+		if s.opts.SSEKMSKeyID != "" {
+			in.SSEKMSKeyId = aws.String(s.opts.SSEKMSKeyID)
+		}
+	}
+	return in
+}
+
+func (s *Store) getObjectInput(key *string) *s3.GetObjectInput {
+	in := &s3.GetObjectInput{
+		Bucket: &s.url.Host,
+		Key:    key,
+	}
+	if alg, k, keyMD5 := s.sseCustomerHeaders(); alg != nil {
+		in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = alg, k, keyMD5
+	}
+	return in
+}
+
 func (s *Store) Store(ctx context.Context, obj []byte) (string, error) {
-	ctx, span := tracing.StartSpan(ctx, "s3.store")
-	defer span.End()
 	id := storeutil.HashObject(obj)
+	if s.cache.HasObject(id) {
+		return id, nil
+	}
+	return s.store(ctx, id, bytes.NewReader(obj), int64(len(obj)))
+}
+
+// StoreReader behaves like Store, but reads the object from r instead
+// of requiring the caller to have it fully in memory already. size
+// must be the exact number of bytes r will yield; it is used both to
+// decide whether to use a multipart upload and to validate the read.
+// The content is spooled to a temporary file on local disk (not kept
+// in memory) while its hash is computed, so callers streaming large
+// objects from disk don't need to materialize the whole object in
+// RAM.
+func (s *Store) StoreReader(ctx context.Context, r io.Reader, size int64) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "s3.store_reader")
+	defer span.End()
+
+	tmp, err := ioutil.TempFile("", "llama-store-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(tmp, io.TeeReader(r, h))
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("s3store: short read: got %d bytes, expected %d", n, size)
+	}
+	id := hex.EncodeToString(h.Sum(nil))
+	span.AddField("object_id", id)
 
 	if s.cache.HasObject(id) {
 		return id, nil
 	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return s.store(ctx, id, tmp, size)
+}
+
+// store uploads size bytes from body -- which must support both Seek
+// (to retry or resume reading from the start of a part) and ReadAt
+// (for concurrent multipart reads) -- to the object named by id,
+// skipping the upload if the object already exists.
+func (s *Store) store(ctx context.Context, id string, body readSeekerAt, size int64) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "s3.store")
+	defer span.End()
 
 	key := aws.String(path.Join(s.url.Path, id))
 	var err error
@@ -89,10 +268,14 @@ func (s *Store) Store(ctx context.Context, obj []byte) (string, error) {
 	defer upload.Rollback()
 
 	if !s.opts.DisableHeadCheck {
-		_, err = s.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		headInput := &s3.HeadObjectInput{
 			Bucket: &s.url.Host,
 			Key:    key,
-		})
+		}
+		if alg, k, keyMD5 := s.sseCustomerHeaders(); alg != nil {
+			headInput.SSECustomerAlgorithm, headInput.SSECustomerKey, headInput.SSECustomerKeyMD5 = alg, k, keyMD5
+		}
+		_, err = s.s3.HeadObjectWithContext(ctx, headInput)
 		if err == nil {
 			upload.Complete()
 			span.AddField("s3.exists", true)
@@ -105,13 +288,17 @@ func (s *Store) Store(ctx context.Context, obj []byte) (string, error) {
 		}
 	}
 
-	span.AddField("s3.write_bytes", len(obj))
+	span.AddField("s3.write_bytes", size)
 
-	_, err = s.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
-		Body:   bytes.NewReader(obj),
-		Bucket: &s.url.Host,
-		Key:    key,
-	})
+	if size > s.multipartThreshold() {
+		if err := s.putMultipart(ctx, key, body, size); err != nil {
+			return "", err
+		}
+		upload.Complete()
+		return id, nil
+	}
+
+	_, err = s.s3.PutObjectWithContext(ctx, s.putObjectInput(key, body))
 	if err != nil {
 		return "", err
 	}
@@ -119,16 +306,154 @@ func (s *Store) Store(ctx context.Context, obj []byte) (string, error) {
 	return id, nil
 }
 
+// readSeekerAt is the interface store needs from an object body: it
+// must be seekable (to rewind before a PutObject retry) and support
+// ReadAt (so putMultipart can hand each part a distinct, concurrently
+// readable view via io.NewSectionReader). Both *bytes.Reader and
+// *os.File satisfy it.
+type readSeekerAt interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+// defaultMultipartThreshold, defaultPartSize, and
+// defaultUploadConcurrency are the defaults for the corresponding
+// Options fields.
+const (
+	defaultMultipartThreshold = 16 << 20 // 16MiB
+	defaultPartSize           = 16 << 20 // 16MiB
+	defaultUploadConcurrency  = 8
+)
+
+func (s *Store) multipartThreshold() int64 {
+	if s.opts.MultipartThreshold > 0 {
+		return s.opts.MultipartThreshold
+	}
+	return defaultMultipartThreshold
+}
+
+func (s *Store) partSize() int64 {
+	if s.opts.PartSize > 0 {
+		return s.opts.PartSize
+	}
+	return defaultPartSize
+}
+
+func (s *Store) uploadConcurrency() int {
+	if s.opts.UploadConcurrency > 0 {
+		return s.opts.UploadConcurrency
+	}
+	return defaultUploadConcurrency
+}
+
+func (s *Store) putMultipart(ctx context.Context, key *string, body io.ReaderAt, size int64) error {
+	ctx, span := tracing.StartSpan(ctx, "s3.put_multipart")
+	defer span.End()
+
+	createInput := &s3.CreateMultipartUploadInput{
+		Bucket: &s.url.Host,
+		Key:    key,
+	}
+	if alg, k, keyMD5 := s.sseCustomerHeaders(); alg != nil {
+		createInput.SSECustomerAlgorithm, createInput.SSECustomerKey, createInput.SSECustomerKeyMD5 = alg, k, keyMD5
+	} else if s.opts.SSE != "" {
+		createInput.ServerSideEncryption = aws.String(s.opts.SSE)
+		if s.opts.SSEKMSKeyID != "" {
+			createInput.SSEKMSKeyId = aws.String(s.opts.SSEKMSKeyID)
+		}
+	}
+	created, err := s.s3.CreateMultipartUploadWithContext(ctx, createInput)
+	if err != nil {
+		return err
+	}
+	uploadID := created.UploadId
+	span.AddField("s3.upload_id", aws.StringValue(uploadID))
+
+	abort := func() {
+		s.s3.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   &s.url.Host,
+			Key:      key,
+			UploadId: uploadID,
+		})
+	}
+
+	partSize := s.partSize()
+	nparts := int((size + partSize - 1) / partSize)
+	parts := make([]*s3.CompletedPart, nparts)
+
+	grp, gctx := errgroup.WithContext(ctx)
+	jobs := make(chan int)
+
+	grp.Go(func() error {
+		defer close(jobs)
+		for i := 0; i < nparts; i++ {
+			select {
+			case jobs <- i:
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+		}
+		return nil
+	})
+	for w := 0; w < s.uploadConcurrency(); w++ {
+		grp.Go(func() error {
+			for idx := range jobs {
+				start := int64(idx) * partSize
+				end := start + partSize
+				if end > size {
+					end = size
+				}
+				partNumber := int64(idx + 1)
+				partInput := &s3.UploadPartInput{
+					Body:       io.NewSectionReader(body, start, end-start),
+					Bucket:     &s.url.Host,
+					Key:        key,
+					PartNumber: &partNumber,
+					UploadId:   uploadID,
+				}
+				if alg, k, keyMD5 := s.sseCustomerHeaders(); alg != nil {
+					partInput.SSECustomerAlgorithm, partInput.SSECustomerKey, partInput.SSECustomerKeyMD5 = alg, k, keyMD5
+				}
+				resp, err := s.s3.UploadPartWithContext(gctx, partInput)
+				if err != nil {
+					return err
+				}
+				parts[idx] = &s3.CompletedPart{
+					ETag:       resp.ETag,
+					PartNumber: &partNumber,
+				}
+			}
+			return nil
+		})
+	}
+
+	if err := grp.Wait(); err != nil {
+		abort()
+		return err
+	}
+
+	_, err = s.s3.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   &s.url.Host,
+		Key:      key,
+		UploadId: uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: parts,
+		},
+	})
+	if err != nil {
+		abort()
+		return err
+	}
+	return nil
+}
+
 const getConcurrency = 32
 
 func (s *Store) getOne(ctx context.Context, id string) ([]byte, error) {
 	ctx, span := tracing.StartSpan(ctx, "s3.get_one")
 	defer span.End()
 
-	resp, err := s.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
-		Bucket: &s.url.Host,
-		Key:    aws.String(path.Join(s.url.Path, id)),
-	})
+	resp, err := s.s3.GetObjectWithContext(ctx, s.getObjectInput(aws.String(path.Join(s.url.Path, id))))
 	if err != nil {
 		return nil, err
 	}
@@ -148,6 +473,296 @@ func (s *Store) getOne(ctx context.Context, id string) ([]byte, error) {
 	return body, nil
 }
 
+// GetObjectStream returns a streaming reader for the object named by
+// id, without buffering its contents in memory. The caller must Close
+// the returned ReadCloser. Because the content hash can only be
+// verified once the object has been fully read, a checksum mismatch
+// surfaces as an error from the Read call that reaches EOF, rather
+// than from GetObjectStream itself.
+func (s *Store) GetObjectStream(ctx context.Context, id string) (io.ReadCloser, error) {
+	ctx, span := tracing.StartSpan(ctx, "s3.get_object_stream")
+	defer span.End()
+
+	resp, err := s.s3.GetObjectWithContext(ctx, s.getObjectInput(aws.String(path.Join(s.url.Path, id))))
+	if err != nil {
+		return nil, err
+	}
+	span.AddField("s3.content_length", aws.Int64Value(resp.ContentLength))
+	return newVerifyingReadCloser(resp.Body, id), nil
+}
+
+// verifyingReadCloser wraps an S3 object body, hashing the bytes read
+// and comparing them against the expected object id once the
+// underlying reader reaches EOF.
+type verifyingReadCloser struct {
+	io.ReadCloser
+	id       string
+	hash     hash.Hash
+	verified bool
+}
+
+func newVerifyingReadCloser(body io.ReadCloser, id string) *verifyingReadCloser {
+	return &verifyingReadCloser{
+		ReadCloser: body,
+		id:         id,
+		hash:       sha256.New(),
+	}
+}
+
+func (v *verifyingReadCloser) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	if n > 0 {
+		v.hash.Write(p[:n])
+	}
+	if err == io.EOF && !v.verified {
+		v.verified = true
+		if got := hex.EncodeToString(v.hash.Sum(nil)); got != v.id {
+			return n, fmt.Errorf("object store mismatch: got csum=%s expected %s", got, v.id)
+		}
+	}
+	return n, err
+}
+
+// GetObjectRange returns a streaming reader for the byte range
+// [offset, offset+length) of the object named by id. A length of 0
+// requests everything from offset to the end of the object. Because
+// the reader only observes part of the object's content, it does not
+// verify the object's checksum; callers that need end-to-end
+// integrity checking should do so once the full object has been
+// assembled, as DownloadFile does.
+func (s *Store) GetObjectRange(ctx context.Context, id string, offset, length int64) (io.ReadCloser, error) {
+	ctx, span := tracing.StartSpan(ctx, "s3.get_object_range")
+	defer span.End()
+
+	in := s.getObjectInput(aws.String(path.Join(s.url.Path, id)))
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+	in.Range = aws.String(byteRange)
+	span.AddField("s3.range", byteRange)
+
+	resp, err := s.s3.GetObjectWithContext(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetRange returns the byte range [offset, offset+length) of the
+// object named by id, buffered fully in memory. A length of 0
+// requests everything from offset to the end of the object. As with
+// GetObjectRange, the result is not checksummed against id, since it
+// only covers part of the object.
+func (s *Store) GetRange(ctx context.Context, id string, offset, length int64) ([]byte, error) {
+	body, err := s.GetObjectRange(ctx, id, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// rangeVerifier accumulates a running hash of an object's bytes as
+// they arrive via a sequence of consecutive, non-overlapping ranges,
+// so that a caller who reads an object that way (as DownloadFile
+// does) doesn't need to re-read it afterwards just to checksum it.
+// Ranges recorded out of order or with a gap poison the verifier,
+// since the running hash can no longer be trusted to reflect the
+// whole object; verifyComplete reports that case as unverified rather
+// than as a mismatch, so callers fall back to checksumming directly.
+//
+// A rangeVerifier is scoped to a single assembly of an object (one
+// DownloadFile call, not one *Store): sharing it across concurrent
+// downloads of the same object id, by the same id-keyed slot, would
+// interleave two independently-correct byte streams into one hash
+// and report a false mismatch for both.
+type rangeVerifier struct {
+	mu       sync.Mutex
+	hash     hash.Hash
+	received int64
+	size     int64
+	broken   bool
+}
+
+func newRangeVerifier() *rangeVerifier {
+	return &rangeVerifier{hash: sha256.New()}
+}
+
+// record feeds data -- the bytes read for the range starting at
+// offset of the size-byte object -- into the running hash, if it
+// picks up exactly where the previous record call left off.
+func (v *rangeVerifier) record(offset int64, data []byte, size int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.broken {
+		return
+	}
+	if offset != v.received {
+		v.broken = true
+		return
+	}
+	v.hash.Write(data)
+	v.received += int64(len(data))
+	v.size = size
+}
+
+// verifyComplete reports whether the ranges recorded via record cover
+// the whole object and hash to id. It returns false, nil -- not an
+// error -- if its ranges don't yet (or no longer, after an
+// out-of-order read) cover the full object; callers should treat that
+// as "unable to verify this way" and fall back to checksumming the
+// object directly, not as a verification failure.
+func (v *rangeVerifier) verifyComplete(id string) (bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.broken || v.size == 0 || v.received != v.size {
+		return false, nil
+	}
+	if got := hex.EncodeToString(v.hash.Sum(nil)); got != id {
+		return false, fmt.Errorf("object store mismatch: got csum=%s expected %s", got, id)
+	}
+	return true, nil
+}
+
+// rangeRecorder is an io.Writer that feeds every byte slice written
+// to it into verifier's running hash, tracking its own position in
+// the object starting from offset.
+type rangeRecorder struct {
+	verifier *rangeVerifier
+	offset   int64
+	size     int64
+}
+
+func (r *rangeRecorder) Write(p []byte) (int, error) {
+	r.verifier.record(r.offset, p, r.size)
+	r.offset += int64(len(p))
+	return len(p), nil
+}
+
+// DownloadFile downloads the object named by id to destPath, using
+// ranged reads. If destPath already contains a partial download from
+// an earlier, interrupted call, DownloadFile resumes after its
+// existing bytes rather than re-fetching them; if it already holds
+// the complete object, no range request is issued at all. Once the
+// download is complete, the file's contents are checksummed against
+// id -- from the ranges just read, when they covered the whole
+// object as one consecutive run, or otherwise by re-reading the file.
+func (s *Store) DownloadFile(ctx context.Context, id, destPath string) error {
+	ctx, span := tracing.StartSpan(ctx, "s3.download_file")
+	defer span.End()
+
+	var offset int64
+	if fi, err := os.Stat(destPath); err == nil {
+		offset = fi.Size()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	span.AddField("s3.resume_offset", offset)
+
+	headInput := &s3.HeadObjectInput{
+		Bucket: &s.url.Host,
+		Key:    aws.String(path.Join(s.url.Path, id)),
+	}
+	if alg, k, keyMD5 := s.sseCustomerHeaders(); alg != nil {
+		headInput.SSECustomerAlgorithm, headInput.SSECustomerKey, headInput.SSECustomerKeyMD5 = alg, k, keyMD5
+	}
+	head, err := s.s3.HeadObjectWithContext(ctx, headInput)
+	if err != nil {
+		return err
+	}
+	size := aws.Int64Value(head.ContentLength)
+	span.AddField("s3.size", size)
+
+	f, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	verifier := newRangeVerifier()
+	if offset < size {
+		// Fetching a range starting at or past the object's size
+		// is an InvalidRange (416) error, not zero bytes -- which
+		// would otherwise happen every time DownloadFile is called
+		// again on an already-complete download.
+		body, err := s.GetObjectRange(ctx, id, offset, 0)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w := io.MultiWriter(f, &rangeRecorder{verifier: verifier, offset: offset, size: size})
+		_, err = io.Copy(w, body)
+		body.Close()
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if ok, err := verifier.verifyComplete(id); err != nil {
+		return err
+	} else if ok {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(destPath)
+	if err != nil {
+		return err
+	}
+	gotId := storeutil.HashObject(data)
+	if gotId != id {
+		return fmt.Errorf("object store mismatch: got csum=%s expected %s", gotId, id)
+	}
+	return nil
+}
+
+// StreamGetRequest is one entry in a GetObjectStreams batch: Id names
+// the object to fetch, and Body/Err are filled in with the result.
+type StreamGetRequest struct {
+	Id   string
+	Body io.ReadCloser
+	Err  error
+}
+
+// GetObjectStreams fills in the Body or Err field of each element of
+// gets, fetching up to getConcurrency objects at a time. It mirrors
+// GetObjects, but hands back a streaming Body for each object instead
+// of buffering it in memory, via GetObjectStream. Callers must Close
+// every non-nil Body; Body is nil exactly when Err is set.
+func (s *Store) GetObjectStreams(ctx context.Context, gets []StreamGetRequest) {
+	ctx, span := tracing.StartSpan(ctx, "s3.get_object_streams")
+	defer span.End()
+	var grp errgroup.Group
+	jobs := make(chan int)
+
+	grp.Go(func() error {
+		defer close(jobs)
+		for i := range gets {
+			jobs <- i
+		}
+		return nil
+	})
+	for i := 0; i < getConcurrency; i++ {
+		grp.Go(func() error {
+			for idx := range jobs {
+				gets[idx].Body, gets[idx].Err = s.GetObjectStream(ctx, gets[idx].Id)
+			}
+			return nil
+		})
+	}
+
+	if err := grp.Wait(); err != nil {
+		log.Fatalf("GetObjectStreams: internal error %s", err)
+	}
+}
+
 func (s *Store) GetObjects(ctx context.Context, gets []store.GetRequest) {
 	ctx, span := tracing.StartSpan(ctx, "s3.get_objects")
 	defer span.End()
@@ -0,0 +1,55 @@
+// Copyright 2020 Nelson Elhage
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3store
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestSSECustomerHeadersUnset(t *testing.T) {
+	s := &Store{}
+	alg, key, keyMD5 := s.sseCustomerHeaders()
+	if alg != nil || key != nil || keyMD5 != nil {
+		t.Fatalf("sseCustomerHeaders() = (%v, %v, %v), want (nil, nil, nil)", alg, key, keyMD5)
+	}
+}
+
+// TestSSECustomerHeadersPassesRawKey guards against re-encoding the
+// key: the SDK's own computeSSEKeys Build handler base64-encodes
+// SSECustomerKey before putting it on the wire, so sseCustomerHeaders
+// must hand back the raw key unchanged, not a pre-encoded copy of it
+// (which the SDK would then encode a second time).
+func TestSSECustomerHeadersPassesRawKey(t *testing.T) {
+	const rawKey = "0123456789abcdef0123456789abcdef"
+	s := &Store{opts: Options{SSECustomerKey: rawKey}}
+
+	alg, key, keyMD5 := s.sseCustomerHeaders()
+	if aws.StringValue(alg) != "AES256" {
+		t.Errorf("algorithm = %q, want AES256", aws.StringValue(alg))
+	}
+	if aws.StringValue(key) != rawKey {
+		t.Errorf("key = %q, want raw key %q unchanged", aws.StringValue(key), rawKey)
+	}
+
+	wantSum := md5.Sum([]byte(rawKey))
+	wantMD5 := base64.StdEncoding.EncodeToString(wantSum[:])
+	if aws.StringValue(keyMD5) != wantMD5 {
+		t.Errorf("keyMD5 = %q, want %q (md5 of the raw key)", aws.StringValue(keyMD5), wantMD5)
+	}
+}